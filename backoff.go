@@ -0,0 +1,193 @@
+/*
+ *
+ * Copyright (c) 2020 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License.
+ *
+ */
+
+package nebula_go
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/JimWen/nebula-go/v3/nebula"
+	graph "github.com/JimWen/nebula-go/v3/nebula/graph"
+)
+
+// BackoffStrategy decides how long to wait before the next retry/reconnect
+// attempt. attempt starts at 1 for the first retry. ok == false means give
+// up instead of retrying again.
+type BackoffStrategy interface {
+	NextDelay(attempt int, err error) (delay time.Duration, ok bool)
+}
+
+// ConstantBackoff retries every IdleTime, up to MaxAttempts (0 = unlimited).
+// It is the fixed-delay behavior the Session retry/reconnect loops used
+// before BackoffStrategy existed.
+type ConstantBackoff struct {
+	IdleTime    time.Duration
+	MaxAttempts int
+}
+
+func (b ConstantBackoff) NextDelay(attempt int, err error) (time.Duration, bool) {
+	if b.MaxAttempts > 0 && attempt >= b.MaxAttempts {
+		return 0, false
+	}
+	return b.IdleTime, true
+}
+
+// ExponentialBackoff doubles BaseDelay on every attempt, capped at MaxDelay,
+// then randomized within a window Jitter (a fraction of the computed delay,
+// 0..1) wide centered on it, i.e. +/- Jitter/2. The jitter is applied after
+// the cap, so a delay can exceed MaxDelay by up to half the window.
+type ExponentialBackoff struct {
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Jitter      float64
+	MaxAttempts int
+}
+
+func (b ExponentialBackoff) NextDelay(attempt int, err error) (time.Duration, bool) {
+	if b.MaxAttempts > 0 && attempt >= b.MaxAttempts {
+		return 0, false
+	}
+
+	// attempt starts at 1, so the first retry waits BaseDelay<<0 == BaseDelay,
+	// not BaseDelay<<1. A shift this large would overflow int64 - check
+	// before shifting, since BaseDelay<<shift can silently wrap to a small
+	// or zero value instead of going negative, which a post-hoc "< 0" check
+	// would miss.
+	shift := attempt - 1
+	if shift < 0 {
+		shift = 0
+	}
+	var delay time.Duration
+	if b.BaseDelay <= 0 {
+		delay = 0
+	} else if shift >= 63 || b.BaseDelay > time.Duration(math.MaxInt64>>uint(shift)) {
+		delay = math.MaxInt64
+	} else {
+		delay = b.BaseDelay << uint(shift)
+	}
+	if b.MaxDelay > 0 && delay > b.MaxDelay {
+		delay = b.MaxDelay
+	}
+
+	if b.Jitter > 0 {
+		jitterRange := float64(delay) * b.Jitter
+		delay = delay - time.Duration(jitterRange/2) + time.Duration(rand.Float64()*jitterRange)
+		if delay < 0 {
+			delay = 0
+		}
+	}
+
+	return delay, true
+}
+
+// DecorrelatedJitterBackoff implements the "decorrelated jitter" retry
+// policy: each delay is a random value between BaseDelay and 3x the previous
+// delay, capped at MaxDelay. See AWS's "Exponential Backoff And Jitter".
+type DecorrelatedJitterBackoff struct {
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	MaxAttempts int
+
+	mu   sync.Mutex
+	prev time.Duration
+}
+
+func (b *DecorrelatedJitterBackoff) NextDelay(attempt int, err error) (time.Duration, bool) {
+	if b.MaxAttempts > 0 && attempt >= b.MaxAttempts {
+		return 0, false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	prev := b.prev
+	if prev == 0 {
+		prev = b.BaseDelay
+	}
+
+	upper := float64(prev) * 3
+	delay := b.BaseDelay + time.Duration(rand.Float64()*(upper-float64(b.BaseDelay)))
+	if b.MaxDelay > 0 && delay > b.MaxDelay {
+		delay = b.MaxDelay
+	}
+
+	b.prev = delay
+	return delay, true
+}
+
+// RetryPolicy configures which errors are retried and how long to wait
+// between attempts. Set it with Session.SetRetryPolicy to replace the fixed
+// RetryConfig/ReconnectConfig IdleTime/MaxTime behavior.
+type RetryPolicy struct {
+	Backoff BackoffStrategy
+
+	// Whitelist, if non-empty, restricts retries to these error codes; any
+	// other error is returned immediately instead of retried.
+	//
+	// Whitelist and Blacklist are matched against the ExecutionResponse that
+	// came back with a failed call. When there is none - always the case on
+	// the ExecuteJson* path - neither list is consulted and the failure is
+	// retried per Backoff.
+	Whitelist []nebula.ErrorCode
+
+	// Blacklist error codes are never retried, even if Whitelist allows
+	// them. E.g. E_SEMANTIC_ERROR never benefits from a retry while
+	// E_RPC_FAILURE usually does.
+	Blacklist []nebula.ErrorCode
+}
+
+func (p *RetryPolicy) allows(code nebula.ErrorCode) bool {
+	for _, c := range p.Blacklist {
+		if c == code {
+			return false
+		}
+	}
+	if len(p.Whitelist) == 0 {
+		return true
+	}
+	for _, c := range p.Whitelist {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRetryPolicy installs a RetryPolicy on the session, overriding the fixed
+// retryCfg.IdleTime/MaxTime behavior for subsequent Execute calls. When the
+// policy has a Backoff, it also replaces reconnectCfg.IdleTime/MaxTime
+// between reconnect attempts; reconnectCfg.MaxTimeDuration still applies.
+func (session *Session) SetRetryPolicy(policy *RetryPolicy) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.retryPolicy = policy
+}
+
+// nextRetryDelay computes the delay before the next retry attempt and
+// whether a retry should happen at all. It consults session.retryPolicy when
+// set; resp may be nil when the caller has no structured response to check
+// error codes against (e.g. the ExecuteJson path). Otherwise it falls back
+// to the legacy fixed retryCfg.IdleTime/MaxTime behavior.
+func (session *Session) nextRetryDelay(attempt int, resp *graph.ExecutionResponse, err error) (time.Duration, bool) {
+	if policy := session.retryPolicy; policy != nil {
+		if resp != nil && !policy.allows(resp.GetErrorCode()) {
+			return 0, false
+		}
+		if policy.Backoff != nil {
+			return policy.Backoff.NextDelay(attempt, err)
+		}
+	}
+
+	if session.retryCfg.MaxTime > 0 && attempt >= session.retryCfg.MaxTime {
+		return 0, false
+	}
+	return session.retryCfg.IdleTime, true
+}
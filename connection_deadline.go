@@ -0,0 +1,60 @@
+/*
+ *
+ * Copyright (c) 2020 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License.
+ *
+ */
+
+package nebula_go
+
+import (
+	"time"
+
+	"github.com/facebook/fbthrift/thrift/lib/go/thrift"
+)
+
+// transportDeadlineSetter is implemented by the raw thrift socket underneath
+// a connection's transport stack.
+type transportDeadlineSetter interface {
+	SetTimeout(timeout time.Duration) error
+}
+
+// transportUnwrapper is implemented by transport wrappers (buffered, framed,
+// header) that expose the transport they wrap, so setDeadline can reach
+// through them to the raw socket underneath.
+type transportUnwrapper interface {
+	UnderlyingTransport() thrift.TTransport
+}
+
+// setDeadline best-effort propagates deadline down to the connection's
+// underlying socket so a blocking thrift call can be unblocked once the
+// context expires, instead of only being observed between retries. It
+// satisfies connDeadlineSetter.
+//
+// This is socket-level cancellation, and it only works when the concrete
+// transport (or something it wraps, via transportUnwrapper) is the raw
+// socket implementing transportDeadlineSetter. The default nebula-go
+// transport stack wraps that socket in a buffered/framed/header transport
+// that does not implement transportUnwrapper, so in the common case this is
+// a no-op: callers still rely on ctx being checked between retries
+// (see doExecuteWithParameter) as the actual cancellation mechanism, not on
+// this reaching the socket.
+func (cn *connection) setDeadline(deadline time.Time) error {
+	if cn == nil || cn.graph == nil {
+		return nil
+	}
+
+	var t thrift.TTransport = cn.graph.Transport
+	for t != nil {
+		if setter, ok := t.(transportDeadlineSetter); ok {
+			return setter.SetTimeout(time.Until(deadline))
+		}
+		unwrapper, ok := t.(transportUnwrapper)
+		if !ok {
+			return nil
+		}
+		t = unwrapper.UnderlyingTransport()
+	}
+	return nil
+}
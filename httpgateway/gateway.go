@@ -0,0 +1,247 @@
+/*
+ *
+ * Copyright (c) 2020 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License.
+ *
+ */
+
+// Package httpgateway embeds a Session/ConnectionPool behind a single HTTP
+// endpoint, mirroring the request/response shapes of nebula-http-gateway so
+// this library can be used as a drop-in embeddable gateway instead of
+// requiring a separate service.
+package httpgateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	nebula "github.com/JimWen/nebula-go/v3"
+)
+
+// defaultSessionTTL bounds how long a per-user Session is kept idle before
+// sessionFor reopens one instead of reusing it. Without this, the sessions
+// map grows by one entry per distinct caller forever - in particular, per
+// distinct RemoteAddr when no AuthFunc is installed.
+const defaultSessionTTL = 10 * time.Minute
+
+// ExecuteRequest mirrors nebula-http-gateway's ExecuteRequest shape.
+type ExecuteRequest struct {
+	Gql       string                 `json:"gql"`
+	ParamList []string               `json:"paramList,omitempty"`
+	ParamMap  map[string]interface{} `json:"paramMap,omitempty"`
+}
+
+// AuthFunc authenticates an incoming request, returning a stable identity
+// used for session affinity. ok == false rejects the request with 401.
+type AuthFunc func(r *http.Request) (user string, ok bool)
+
+// Gateway serves Session.ExecuteJsonWithParameter over HTTP.
+//
+// With a ConnectionPool, it holds one Nebula session per authenticated user
+// so repeated requests from the same caller reuse the same graph session
+// instead of opening a new one per call; idle sessions are released and
+// evicted after sessionTTL. With a SessionPool, there is no per-user session
+// to hold onto - SessionPool already executes each call against a session it
+// manages internally - so every request is simply run through the pool.
+type Gateway struct {
+	pool     *nebula.ConnectionPool
+	sessPool *nebula.SessionPool
+	username string
+	password string
+	auth     AuthFunc
+
+	sessionTTL time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*cachedSession
+}
+
+// cachedSession is a pool-backed Session kept alive for a user between
+// requests, along with when it was last used so sessionFor can evict it.
+// inUse counts requests currently executing against session; sessionFor's
+// TTL sweep skips entries with inUse > 0 so a slow request's session is
+// never released out from under it.
+type cachedSession struct {
+	session    *nebula.Session
+	lastUsedAt time.Time
+	inUse      int
+}
+
+// NewGateway wraps a ConnectionPool. username/password are the Nebula
+// credentials used to open a session on behalf of each gateway caller.
+func NewGateway(pool *nebula.ConnectionPool, username, password string) *Gateway {
+	return &Gateway{
+		pool:       pool,
+		username:   username,
+		password:   password,
+		sessionTTL: defaultSessionTTL,
+		sessions:   make(map[string]*cachedSession),
+	}
+}
+
+// NewGatewayWithSessionPool wraps an already-configured SessionPool, which
+// owns its own credentials. Requests are executed directly through the pool
+// on every call - SessionPool has no public session-checkout API to cache a
+// session from, only Execute*-style methods that manage sessions internally.
+func NewGatewayWithSessionPool(sessPool *nebula.SessionPool) *Gateway {
+	return &Gateway{
+		sessPool: sessPool,
+	}
+}
+
+// Use installs an auth middleware. Without one, every request is served
+// under a session keyed by the caller's RemoteAddr.
+func (g *Gateway) Use(auth AuthFunc) {
+	g.auth = auth
+}
+
+// ServeHTTP implements http.Handler, accepting an ExecuteRequest body and
+// returning the same JSON shape as Session.ExecuteJson.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := r.RemoteAddr
+	if g.auth != nil {
+		u, ok := g.auth(r)
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		user = u
+	}
+
+	var req ExecuteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params, err := parseParamList(req.ParamList)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for k, v := range req.ParamMap {
+		params[k] = v
+	}
+
+	var resp []byte
+	if g.sessPool != nil {
+		resp, err = g.sessPool.ExecuteJsonWithParameter(req.Gql, params)
+	} else {
+		var session *nebula.Session
+		session, err = g.sessionFor(user)
+		if err == nil {
+			resp, err = session.ExecuteJsonWithParameter(req.Gql, params)
+			g.releaseSession(user)
+		}
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(resp)
+}
+
+// sessionFor returns the session affined to user, opening one on first use
+// and evicting/releasing any session that has been idle past g.sessionTTL -
+// both the requested user's and, while the lock is held, any others found
+// stale, so the map doesn't grow without bound. It marks the returned
+// session in-use; callers must call releaseSession(user) once they're done
+// with it so a later sweep is allowed to evict it.
+func (g *Gateway) sessionFor(user string) (*nebula.Session, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	ttl := g.sessionTTL
+	if ttl <= 0 {
+		ttl = defaultSessionTTL
+	}
+	for u, cached := range g.sessions {
+		if cached.inUse == 0 && now.Sub(cached.lastUsedAt) > ttl {
+			cached.session.Release()
+			delete(g.sessions, u)
+		}
+	}
+
+	if cached, ok := g.sessions[user]; ok {
+		cached.lastUsedAt = now
+		cached.inUse++
+		return cached.session, nil
+	}
+
+	session, err := g.pool.GetSession(g.username, g.password)
+	if err != nil {
+		return nil, err
+	}
+
+	g.sessions[user] = &cachedSession{session: session, lastUsedAt: now, inUse: 1}
+	return session, nil
+}
+
+// releaseSession marks the session sessionFor handed out for user as no
+// longer in use, making it eligible for eviction by a future TTL sweep. It
+// refreshes lastUsedAt so the idle countdown starts from when the request
+// actually finished, not when it started.
+func (g *Gateway) releaseSession(user string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if cached, ok := g.sessions[user]; ok {
+		cached.inUse--
+		cached.lastUsedAt = time.Now()
+	}
+}
+
+// parseParamList parses "k=v" strings as nGQL literals (e.g. `limit=10`,
+// `name="bob"`, `active=true`) so callers can pass typed values without JSON
+// gymnastics.
+func parseParamList(paramList []string) (map[string]interface{}, error) {
+	params := make(map[string]interface{}, len(paramList))
+	for _, kv := range paramList {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid paramList entry %q, expected k=v", kv)
+		}
+
+		v, err := parseNGQLLiteral(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("paramList entry %q: %w", kv, err)
+		}
+		params[parts[0]] = v
+	}
+	return params, nil
+}
+
+// parseNGQLLiteral parses a single nGQL literal: a double-quoted string,
+// true/false, or a number.
+func parseNGQLLiteral(raw string) (interface{}, error) {
+	if len(raw) >= 2 && raw[0] == '"' && raw[len(raw)-1] == '"' {
+		return raw[1 : len(raw)-1], nil
+	}
+	if raw == "true" {
+		return true, nil
+	}
+	if raw == "false" {
+		return false, nil
+	}
+	if i, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(raw, 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("unrecognized literal %q", raw)
+}
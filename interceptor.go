@@ -0,0 +1,72 @@
+/*
+ *
+ * Copyright (c) 2020 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License.
+ *
+ */
+
+package nebula_go
+
+import (
+	"context"
+)
+
+// ExecuteFunc is the query execution being wrapped by an ExecuteInterceptor.
+type ExecuteFunc func(ctx context.Context, stmt string, params map[string]interface{}) (*ResultSet, error)
+
+// ExecuteInterceptor wraps a single Execute/ExecuteWithParameter call,
+// letting cross-cutting concerns (OpenTelemetry spans, Prometheus counters,
+// slow-query logging, parameter redaction, ...) run around the query without
+// forking the client. Interceptors are composed like HTTP middleware: each
+// one decides whether/when to call next, and may inspect or replace its
+// result.
+type ExecuteInterceptor func(ctx context.Context, stmt string, params map[string]interface{}, next ExecuteFunc) (*ResultSet, error)
+
+// ReconnectEvent is emitted at reconnect boundaries so operators can alarm
+// on the "Nebula Down" path, which otherwise only surfaces as a returned
+// error from whichever call triggered the reconnect.
+type ReconnectEvent struct {
+	SessionID int64
+	Attempt   int
+	Err       error // nil on success
+}
+
+// ReconnectEventHandler is invoked once per reconnect attempt.
+type ReconnectEventHandler func(event ReconnectEvent)
+
+// UseInterceptor appends interceptor to the session's chain. Interceptors
+// run in registration order, each wrapping the next, with the innermost
+// call being the actual query execution.
+func (session *Session) UseInterceptor(interceptor ExecuteInterceptor) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.interceptors = append(session.interceptors, interceptor)
+}
+
+// OnReconnect registers a handler invoked at every reconnect attempt,
+// successful or not. Only one handler may be registered; calling this again
+// replaces the previous handler.
+func (session *Session) OnReconnect(handler ReconnectEventHandler) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.onReconnect = handler
+}
+
+// runWithInterceptors executes f wrapped by the session's interceptor chain.
+func (session *Session) runWithInterceptors(ctx context.Context, stmt string, params map[string]interface{}, f ExecuteFunc) (*ResultSet, error) {
+	session.mu.Lock()
+	interceptors := make([]ExecuteInterceptor, len(session.interceptors))
+	copy(interceptors, session.interceptors)
+	session.mu.Unlock()
+
+	chained := f
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor := interceptors[i]
+		next := chained
+		chained = func(ctx context.Context, stmt string, params map[string]interface{}) (*ResultSet, error) {
+			return interceptor(ctx, stmt, params, next)
+		}
+	}
+	return chained(ctx, stmt, params)
+}
@@ -0,0 +1,231 @@
+/*
+ *
+ * Copyright (c) 2020 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License.
+ *
+ */
+
+package nebula_go
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/JimWen/nebula-go/v3/nebula"
+)
+
+// defaultPreparedStatementCacheSize bounds how many PreparedStatements a
+// Session keeps around before evicting the least recently used one.
+const defaultPreparedStatementCacheSize = 128
+
+// parsePlaceholderNames returns the distinct named parameters referenced in
+// stmt outside of any quoted string literal, in first-occurrence order, e.g.
+// "GO FROM $id OVER serve" -> ["id"]. A $word inside a string literal (e.g.
+// RETURN "cost is $amount") is not a placeholder and is skipped. This is the
+// one-time "parsing" a prepared statement buys: the statement text itself is
+// only scanned once, in Session.Prepare, rather than on every Execute.
+func parsePlaceholderNames(stmt string) []string {
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+
+	var quote byte
+	for i := 0; i < len(stmt); i++ {
+		c := stmt[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if c == '"' || c == '\'' {
+			quote = c
+			continue
+		}
+		if c != '$' {
+			continue
+		}
+
+		j := i + 1
+		for j < len(stmt) && isPlaceholderByte(stmt[j], j == i+1) {
+			j++
+		}
+		if j == i+1 {
+			continue
+		}
+		name := stmt[i+1 : j]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+		i = j - 1
+	}
+	return names
+}
+
+func isPlaceholderByte(c byte, first bool) bool {
+	switch {
+	case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return true
+	case !first && c >= '0' && c <= '9':
+		return true
+	default:
+		return false
+	}
+}
+
+// preparedSkeletonKey is the context key doExecuteWithParameter checks to
+// find a PreparedStatement's cached nebula.Value skeleton to rebind instead
+// of converting params from scratch.
+type preparedSkeletonKey struct{}
+
+// PreparedStatement is a query template returned by Session.Prepare. Its
+// placeholder names are extracted from stmt once, up front, and a
+// nebula.Value is allocated once per placeholder; Execute rebinds those same
+// nebula.Value structs in place on every call instead of converting params
+// into a fresh map from scratch.
+type PreparedStatement struct {
+	stmt       string
+	paramNames []string
+	session    *Session
+
+	mu       sync.Mutex
+	skeleton map[string]*nebula.Value
+}
+
+// Execute rebinds params into the statement's cached nebula.Value skeleton
+// in place - reusing the map and nebula.Value structs allocated by Prepare
+// instead of allocating a fresh set on every call - then runs the statement.
+// Concurrent Execute calls on the same PreparedStatement are serialized,
+// since they'd otherwise race on the shared skeleton. Prepare returns this
+// same cached PreparedStatement for repeated calls with the same stmt on one
+// Session.
+func (p *PreparedStatement) Execute(params map[string]interface{}) (*ResultSet, error) {
+	if err := p.validateParams(params); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ctx := context.WithValue(context.Background(), preparedSkeletonKey{}, p.skeleton)
+	return p.session.ExecuteWithParameterContext(ctx, p.stmt, params)
+}
+
+// validateParams only checks that every placeholder the statement actually
+// references has a value; unrelated extra keys in params are ignored, same
+// as plain ExecuteWithParameter, so callers can reuse one params map across
+// several prepared statements.
+func (p *PreparedStatement) validateParams(params map[string]interface{}) error {
+	for _, name := range p.paramNames {
+		if _, ok := params[name]; !ok {
+			return fmt.Errorf("failed to execute: missing parameter %q for prepared statement", name)
+		}
+	}
+	return nil
+}
+
+// rebindPreparedParams overwrites every *nebula.Value in skeleton with the
+// corresponding entry from params, reusing the existing nebula.Value structs
+// via bindNvalue instead of allocating new ones.
+func rebindPreparedParams(skeleton map[string]*nebula.Value, params map[string]interface{}, tz timezoneInfo) error {
+	for name, dst := range skeleton {
+		v, ok := params[name]
+		if !ok {
+			return fmt.Errorf("failed to execute: missing parameter %q for prepared statement", name)
+		}
+		if err := bindNvalue(dst, v, tz); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// preparedStatementCache is a small LRU cache of PreparedStatements keyed by
+// the raw query string.
+type preparedStatementCache struct {
+	mu       sync.Mutex
+	size     int
+	ll       *list.List
+	elements map[string]*list.Element
+}
+
+type preparedStatementCacheEntry struct {
+	stmt string
+	ps   *PreparedStatement
+}
+
+func newPreparedStatementCache(size int) *preparedStatementCache {
+	if size <= 0 {
+		size = defaultPreparedStatementCacheSize
+	}
+	return &preparedStatementCache{
+		size:     size,
+		ll:       list.New(),
+		elements: make(map[string]*list.Element),
+	}
+}
+
+func (c *preparedStatementCache) get(stmt string) (*PreparedStatement, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ele, ok := c.elements[stmt]; ok {
+		c.ll.MoveToFront(ele)
+		return ele.Value.(*preparedStatementCacheEntry).ps, true
+	}
+	return nil, false
+}
+
+func (c *preparedStatementCache) add(stmt string, ps *PreparedStatement) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ele, ok := c.elements[stmt]; ok {
+		c.ll.MoveToFront(ele)
+		ele.Value.(*preparedStatementCacheEntry).ps = ps
+		return
+	}
+	ele := c.ll.PushFront(&preparedStatementCacheEntry{stmt: stmt, ps: ps})
+	c.elements[stmt] = ele
+	for c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		if oldest == nil {
+			break
+		}
+		c.ll.Remove(oldest)
+		delete(c.elements, oldest.Value.(*preparedStatementCacheEntry).stmt)
+	}
+}
+
+// Prepare returns a PreparedStatement for stmt, reusing a cached one keyed
+// by the raw query string when available so repeated hot queries don't pay
+// for parameter-parsing setup on every call.
+func (session *Session) Prepare(stmt string) (*PreparedStatement, error) {
+	session.mu.Lock()
+	if session.connection == nil {
+		session.mu.Unlock()
+		return nil, fmt.Errorf("failed to prepare: Session has been released")
+	}
+	if session.psCache == nil {
+		session.psCache = newPreparedStatementCache(defaultPreparedStatementCacheSize)
+	}
+	cache := session.psCache
+	session.mu.Unlock()
+
+	if ps, ok := cache.get(stmt); ok {
+		return ps, nil
+	}
+
+	names := parsePlaceholderNames(stmt)
+	skeleton := make(map[string]*nebula.Value, len(names))
+	for _, name := range names {
+		skeleton[name] = nebula.NewValue()
+	}
+
+	ps := &PreparedStatement{stmt: stmt, paramNames: names, session: session, skeleton: skeleton}
+	cache.add(stmt, ps)
+	return ps, nil
+}
@@ -9,7 +9,9 @@
 package nebula_go
 
 import (
+	"context"
 	"fmt"
+	"reflect"
 	"sync"
 	"time"
 
@@ -34,9 +36,13 @@ type Session struct {
 	timezoneInfo
 	reconnectCfg ReconnectConfig
 	retryCfg     RetryConfig
+	psCache      *preparedStatementCache // lazily created by Prepare
+	interceptors []ExecuteInterceptor
+	onReconnect  ReconnectEventHandler
+	retryPolicy  *RetryPolicy // optional, overrides retryCfg's fixed IdleTime/MaxTime when set
 }
 
-func (session *Session) reconnectWithExecuteErr(resp *graph.ExecutionResponse, err error) error {
+func (session *Session) reconnectWithExecuteErr(ctx context.Context, resp *graph.ExecutionResponse, err error) error {
 	// Reconnect only if the transport is closed
 	_, ok := err.(thrift.TransportException)
 	if !ok && !IsServerSessionError(resp) {
@@ -44,14 +50,21 @@ func (session *Session) reconnectWithExecuteErr(resp *graph.ExecutionResponse, e
 	}
 
 	retryTime := 0
+	attempt := 0
 	startRetryTime := time.Now()
 
 	var _err error = nil
 
 	for {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		attempt++
 
 		if _err = session.reConnect(); _err != nil {
 			session.log.Error(fmt.Sprintf("failed to reconnect, %s", _err.Error()))
+			session.emitReconnectEvent(ReconnectEvent{SessionID: session.sessionID, Attempt: attempt, Err: _err})
 
 			if session.reconnectCfg.MaxTimeDuration != 0 {
 				retryTimeDuration := time.Since(startRetryTime)
@@ -60,6 +73,17 @@ func (session *Session) reconnectWithExecuteErr(resp *graph.ExecutionResponse, e
 				}
 			}
 
+			if policy := session.retryPolicy; policy != nil && policy.Backoff != nil {
+				delay, retry := policy.Backoff.NextDelay(attempt, _err)
+				if !retry {
+					break
+				}
+				if sleepErr := sleepOrDone(ctx, delay); sleepErr != nil {
+					return sleepErr
+				}
+				continue
+			}
+
 			if session.reconnectCfg.MaxTime != 0 {
 				retryTime += 1
 				if retryTime >= session.reconnectCfg.MaxTime {
@@ -67,7 +91,9 @@ func (session *Session) reconnectWithExecuteErr(resp *graph.ExecutionResponse, e
 				}
 			}
 
-			time.Sleep(session.reconnectCfg.IdleTime)
+			if sleepErr := sleepOrDone(ctx, session.reconnectCfg.IdleTime); sleepErr != nil {
+				return sleepErr
+			}
 		} else {
 			break
 		}
@@ -79,11 +105,20 @@ func (session *Session) reconnectWithExecuteErr(resp *graph.ExecutionResponse, e
 	} else {
 		session.log.Info(fmt.Sprintf("Successfully reconnect to host: %s, port: %d",
 			session.connection.severAddress.Host, session.connection.severAddress.Port))
+		session.emitReconnectEvent(ReconnectEvent{SessionID: session.sessionID, Attempt: attempt})
 		return nil
 	}
 }
 
-func (session *Session) executeWithReconnect(f func() (interface{}, error)) (interface{}, error) {
+// emitReconnectEvent notifies the session's ReconnectEventHandler, if any,
+// of a reconnect attempt.
+func (session *Session) emitReconnectEvent(event ReconnectEvent) {
+	if session.onReconnect != nil {
+		session.onReconnect(event)
+	}
+}
+
+func (session *Session) executeWithReconnect(ctx context.Context, f func() (interface{}, error)) (interface{}, error) {
 	resp, err := f()
 
 	var param *graph.ExecutionResponse = nil
@@ -96,7 +131,7 @@ func (session *Session) executeWithReconnect(f func() (interface{}, error)) (int
 		return resp, nil
 	}
 
-	if err2 := session.reconnectWithExecuteErr(param, err); err2 != nil {
+	if err2 := session.reconnectWithExecuteErr(ctx, param, err); err2 != nil {
 		return nil, err2
 	}
 
@@ -105,16 +140,67 @@ func (session *Session) executeWithReconnect(f func() (interface{}, error)) (int
 
 }
 
+// sleepOrDone sleeps for d unless ctx is cancelled first, in which case it
+// returns ctx.Err() without waiting out the rest of d.
+func sleepOrDone(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // ExecuteWithParameter returns the result of the given query as a ResultSet
 func (session *Session) ExecuteWithParameter(stmt string, params map[string]interface{}) (*ResultSet, error) {
+	return session.runWithInterceptors(context.Background(), stmt, params, session.doExecuteWithParameter)
+}
+
+// ExecuteWithContext is ExecuteWithParameter with no bound parameters, plus
+// per-call cancellation: ctx is checked before every retry sleep and every
+// reconnect attempt, and its deadline, if any, is handed to the connection's
+// setDeadline. That reaches the socket only when the transport stack exposes
+// it (see setDeadline); otherwise a thrift call already in flight is not
+// interrupted and ctx takes effect at the next check.
+func (session *Session) ExecuteWithContext(ctx context.Context, stmt string) (*ResultSet, error) {
+	return session.ExecuteWithParameterContext(ctx, stmt, map[string]interface{}{})
+}
+
+// ExecuteWithParameterContext is ExecuteWithParameter with an explicit
+// context for cancellation, see ExecuteWithContext.
+func (session *Session) ExecuteWithParameterContext(ctx context.Context, stmt string, params map[string]interface{}) (*ResultSet, error) {
+	return session.runWithInterceptors(ctx, stmt, params, session.doExecuteWithParameter)
+}
+
+// connDeadlineSetter is implemented by connections that can propagate a
+// context's deadline down to the underlying transport's socket deadline.
+type connDeadlineSetter interface {
+	setDeadline(deadline time.Time) error
+}
+
+// doExecuteWithParameter is the actual query execution. It is wrapped by
+// runWithInterceptors so that registered ExecuteInterceptors observe and can
+// alter every call.
+func (session *Session) doExecuteWithParameter(ctx context.Context, stmt string, params map[string]interface{}) (*ResultSet, error) {
 	session.mu.Lock()
 	defer session.mu.Unlock()
 	if session.connection == nil {
 		return nil, fmt.Errorf("failed to execute: Session has been released")
 	}
-	paramsMap, err := parseParams(params)
-	if err != nil {
-		return nil, err
+	var paramsMap map[string]*nebula.Value
+	if skeleton, ok := ctx.Value(preparedSkeletonKey{}).(map[string]*nebula.Value); ok {
+		if err := rebindPreparedParams(skeleton, params, session.timezoneInfo); err != nil {
+			return nil, err
+		}
+		paramsMap = skeleton
+	} else {
+		var err error
+		paramsMap, err = parseParamsWithZone(params, session.timezoneInfo)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	execFunc := func() (interface{}, error) {
@@ -125,6 +211,18 @@ func (session *Session) ExecuteWithParameter(stmt string, params map[string]inte
 		var err error = nil
 
 		for {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+
+			if deadline, ok := ctx.Deadline(); ok {
+				if setter, ok := interface{}(session.connection).(connDeadlineSetter); ok {
+					if derr := setter.setDeadline(deadline); derr != nil {
+						return nil, derr
+					}
+				}
+			}
+
 			resp, err = session.connection.executeWithParameter(session.sessionID, stmt, paramsMap)
 			if IsQueryOk(err, resp) {
 				break
@@ -136,15 +234,16 @@ func (session *Session) ExecuteWithParameter(stmt string, params map[string]inte
 				break
 			}
 
-			if session.retryCfg.MaxTime > 0 {
-				retryTime += 1
-				if retryTime >= session.retryCfg.MaxTime {
-					break
-				}
+			retryTime += 1
+			delay, retry := session.nextRetryDelay(retryTime, resp, err)
+			if !retry {
+				break
 			}
 
 			session.log.Error(fmt.Sprintf("start retry, %s", err.Error()))
-			time.Sleep(session.retryCfg.IdleTime)
+			if sleepErr := sleepOrDone(ctx, delay); sleepErr != nil {
+				return nil, sleepErr
+			}
 		}
 
 		if err != nil {
@@ -159,7 +258,7 @@ func (session *Session) ExecuteWithParameter(stmt string, params map[string]inte
 		return resSet, nil
 	}
 
-	resp, err := session.executeWithReconnect(execFunc)
+	resp, err := session.executeWithReconnect(ctx, execFunc)
 	if err != nil {
 		return nil, err
 	}
@@ -239,6 +338,18 @@ func (session *Session) ExecuteJson(stmt string) ([]byte, error) {
 // Date and Datetime will be returned in UTC
 // The result is a JSON string in the same format as ExecuteJson()
 func (session *Session) ExecuteJsonWithParameter(stmt string, params map[string]interface{}) ([]byte, error) {
+	return session.ExecuteJsonWithParameterContext(context.Background(), stmt, params)
+}
+
+// ExecuteJsonWithContext is ExecuteJson with no bound parameters, plus
+// per-call cancellation, see ExecuteWithContext.
+func (session *Session) ExecuteJsonWithContext(ctx context.Context, stmt string) ([]byte, error) {
+	return session.ExecuteJsonWithParameterContext(ctx, stmt, map[string]interface{}{})
+}
+
+// ExecuteJsonWithParameterContext is ExecuteJsonWithParameter with an
+// explicit context for cancellation, see ExecuteWithContext.
+func (session *Session) ExecuteJsonWithParameterContext(ctx context.Context, stmt string, params map[string]interface{}) ([]byte, error) {
 	session.mu.Lock()
 	defer session.mu.Unlock()
 	if session.connection == nil {
@@ -247,7 +358,7 @@ func (session *Session) ExecuteJsonWithParameter(stmt string, params map[string]
 
 	paramsMap := make(map[string]*nebula.Value)
 	for k, v := range params {
-		nv, er := value2Nvalue(v)
+		nv, er := value2Nvalue(v, session.timezoneInfo)
 		if er != nil {
 			return nil, er
 		}
@@ -262,6 +373,18 @@ func (session *Session) ExecuteJsonWithParameter(stmt string, params map[string]
 		var err error = nil
 
 		for {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+
+			if deadline, ok := ctx.Deadline(); ok {
+				if setter, ok := interface{}(session.connection).(connDeadlineSetter); ok {
+					if derr := setter.setDeadline(deadline); derr != nil {
+						return nil, derr
+					}
+				}
+			}
+
 			resp, err = session.connection.ExecuteJsonWithParameter(session.sessionID, stmt, paramsMap)
 			if err == nil {
 				return resp, nil
@@ -273,20 +396,21 @@ func (session *Session) ExecuteJsonWithParameter(stmt string, params map[string]
 				return nil, err
 			}
 
-			if session.retryCfg.MaxTime > 0 {
-				retryTime += 1
-				if retryTime >= session.retryCfg.MaxTime {
-					return nil, err
-				}
+			retryTime += 1
+			delay, retry := session.nextRetryDelay(retryTime, nil, err)
+			if !retry {
+				return nil, err
 			}
 
 			session.log.Error(fmt.Sprintf("start retry, %s", err.Error()))
-			time.Sleep(session.retryCfg.IdleTime)
+			if sleepErr := sleepOrDone(ctx, delay); sleepErr != nil {
+				return nil, sleepErr
+			}
 		}
 
 	}
 
-	resp, err := session.executeWithReconnect(execFunc)
+	resp, err := session.executeWithReconnect(ctx, execFunc)
 	if err != nil {
 		return nil, err
 	}
@@ -391,12 +515,13 @@ func IsServerSessionError(resp *graph.ExecutionResponse) bool {
 	return resp != nil && (resp.GetErrorCode() == nebula.ErrorCode_E_SESSION_INVALID || resp.GetErrorCode() == nebula.ErrorCode_E_SESSION_TIMEOUT)
 }
 
-// construct Slice to nebula.NList
-func slice2Nlist(list []interface{}) (*nebula.NList, error) {
+// construct Slice to nebula.NList. tz is forwarded to value2Nvalue for any
+// time.Time elements.
+func slice2Nlist(list []interface{}, tz ...timezoneInfo) (*nebula.NList, error) {
 	sv := []*nebula.Value{}
 	var ret nebula.NList
 	for _, item := range list {
-		nv, er := value2Nvalue(item)
+		nv, er := value2Nvalue(item, tz...)
 		if er != nil {
 			return nil, er
 		}
@@ -406,10 +531,11 @@ func slice2Nlist(list []interface{}) (*nebula.NList, error) {
 	return &ret, nil
 }
 
-// construct map to nebula.NMap
-func map2Nmap(m map[string]interface{}) (*nebula.NMap, error) {
+// construct map to nebula.NMap. tz is forwarded to value2Nvalue for any
+// time.Time values.
+func map2Nmap(m map[string]interface{}, tz ...timezoneInfo) (*nebula.NMap, error) {
 	var ret nebula.NMap
-	kvs, err := parseParams(m)
+	kvs, err := parseParamsWithZone(m, tzOrZero(tz))
 	if err != nil {
 		return nil, err
 	}
@@ -417,14 +543,182 @@ func map2Nmap(m map[string]interface{}) (*nebula.NMap, error) {
 	return &ret, nil
 }
 
-// construct go-type to nebula.Value
-func value2Nvalue(any interface{}) (value *nebula.Value, err error) {
-	value = nebula.NewValue()
+// tzOrZero returns tz[0], or the zero timezoneInfo (UTC) when no tz was
+// supplied - the same "no session in scope" default value2Nvalue uses.
+func tzOrZero(tz []timezoneInfo) timezoneInfo {
+	if len(tz) > 0 {
+		return tz[0]
+	}
+	return timezoneInfo{}
+}
+
+// parseParamsWithZone converts every value in params to a nebula.Value,
+// threading tz through so time.Time values are converted in the session's
+// zone end to end - including nested values inside a []interface{} or
+// map[string]interface{} param, via value2Nvalue's own tz-aware recursion.
+func parseParamsWithZone(params map[string]interface{}, tz timezoneInfo) (map[string]*nebula.Value, error) {
+	ret := make(map[string]*nebula.Value, len(params))
+	for k, v := range params {
+		nv, err := value2Nvalue(v, tz)
+		if err != nil {
+			return nil, err
+		}
+		ret[k] = nv
+	}
+	return ret, nil
+}
+
+// ValueConverter converts a Go value into a nebula.Value. ok should be false
+// when the converter doesn't recognize the value, so value2Nvalue can fall
+// through to the next converter (or its own built-in handling).
+type ValueConverter func(any interface{}) (value *nebula.Value, ok bool, err error)
+
+var (
+	valueConvertersMu sync.RWMutex
+	valueConverters   []ValueConverter
+)
+
+// RegisterValueConverter adds a custom conversion function that value2Nvalue
+// (and therefore ExecuteWithParameter/ExecuteJsonWithParameter) consults
+// before falling back to its own built-in type switch. Converters are tried
+// in registration order; the first one that reports ok == true wins. This
+// lets applications plug in domain-specific conversions without forking.
+func RegisterValueConverter(converter ValueConverter) {
+	valueConvertersMu.Lock()
+	defer valueConvertersMu.Unlock()
+	valueConverters = append(valueConverters, converter)
+}
+
+// struct2Nmap converts an exported-field struct into a nebula.NMap, keyed by
+// field name, via reflection. tz is forwarded to value2Nvalue for any
+// time.Time fields.
+func struct2Nmap(v reflect.Value, tz ...timezoneInfo) (*nebula.NMap, error) {
+	t := v.Type()
+	kvs := make(map[string]*nebula.Value, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			// unexported field
+			continue
+		}
+		nv, err := value2Nvalue(v.Field(i).Interface(), tz...)
+		if err != nil {
+			return nil, err
+		}
+		kvs[field.Name] = nv
+	}
+	return &nebula.NMap{Kvs: kvs}, nil
+}
+
+// time2NDateTime converts a time.Time to a nebula.DateTime expressed in the
+// session's timezoneInfo, matching the zone convention genResultSet uses
+// when converting a returned nebula.DateTime back into a time.Time. tz's
+// zero value is UTC, which is also the documented contract for values that
+// come back from ExecuteJson/ExecuteJsonWithParameter.
+func (tz timezoneInfo) time2NDateTime(v time.Time) *nebula.DateTime {
+	v = v.In(time.FixedZone(string(tz.name), int(tz.offset)))
+	return &nebula.DateTime{
+		Year:     int16(v.Year()),
+		Month:    int8(v.Month()),
+		Day:      int8(v.Day()),
+		Hour:     int8(v.Hour()),
+		Minute:   int8(v.Minute()),
+		Sec:      int8(v.Second()),
+		Microsec: int32(v.Nanosecond() / 1000),
+	}
+}
+
+// construct go-type to nebula.Value. tz is the session's timezoneInfo to use
+// for time.Time values, and is forwarded into nested lists, maps and structs;
+// callers that pass no tz get UTC, matching the documented "returned in UTC"
+// contract.
+func value2Nvalue(any interface{}, tz ...timezoneInfo) (*nebula.Value, error) {
+	value := nebula.NewValue()
+	if err := bindNvalue(value, any, tz...); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// resetNvalue clears every field bindNvalue may have set on a previous call,
+// so rebinding a reused *nebula.Value (see PreparedStatement) can't leak the
+// previous call's type into this one.
+func resetNvalue(value *nebula.Value) {
+	value.BVal = nil
+	value.IVal = nil
+	value.FVal = nil
+	value.SVal = nil
+	value.NVal = nil
+	value.LVal = nil
+	value.MVal = nil
+	value.DVal = nil
+	value.TVal = nil
+	value.DtVal = nil
+	value.DuVal = nil
+	value.GgVal = nil
+}
+
+// bindNvalue converts any into value in place, so a caller already holding
+// a *nebula.Value (a PreparedStatement's cached skeleton, see
+// prepared_statement.go) can rebind it on every Execute instead of
+// allocating a new nebula.Value each time. tz is the session's timezoneInfo
+// to use for time.Time values, and is forwarded into nested lists, maps and
+// structs; callers that pass no tz get UTC, matching the documented
+// "returned in UTC" contract.
+func bindNvalue(value *nebula.Value, any interface{}, tz ...timezoneInfo) (err error) {
+	valueConvertersMu.RLock()
+	converters := valueConverters
+	valueConvertersMu.RUnlock()
+	for _, converter := range converters {
+		if v, ok, cerr := converter(any); ok {
+			if cerr != nil {
+				return cerr
+			}
+			*value = *v
+			return nil
+		}
+	}
+
+	resetNvalue(value)
 	if v, ok := any.(bool); ok {
 		value.BVal = &v
 	} else if v, ok := any.(int); ok {
 		ival := int64(v)
 		value.IVal = &ival
+	} else if v, ok := any.(int8); ok {
+		ival := int64(v)
+		value.IVal = &ival
+	} else if v, ok := any.(int16); ok {
+		ival := int64(v)
+		value.IVal = &ival
+	} else if v, ok := any.(int32); ok {
+		ival := int64(v)
+		value.IVal = &ival
+	} else if v, ok := any.(int64); ok {
+		value.IVal = &v
+	} else if v, ok := any.(uint); ok {
+		ival := int64(v)
+		value.IVal = &ival
+	} else if v, ok := any.(uint8); ok {
+		ival := int64(v)
+		value.IVal = &ival
+	} else if v, ok := any.(uint16); ok {
+		ival := int64(v)
+		value.IVal = &ival
+	} else if v, ok := any.(uint32); ok {
+		ival := int64(v)
+		value.IVal = &ival
+	} else if v, ok := any.(uint64); ok {
+		ival := int64(v)
+		value.IVal = &ival
+	} else if v, ok := any.([]byte); ok {
+		value.SVal = v
+	} else if v, ok := any.(time.Time); ok {
+		var zone timezoneInfo
+		if len(tz) > 0 {
+			zone = tz[0]
+		}
+		value.SetDtVal(zone.time2NDateTime(v))
 	} else if v, ok := any.(float64); ok {
 		if v == float64(int64(v)) {
 			iv := int64(v)
@@ -446,19 +740,19 @@ func value2Nvalue(any interface{}) (value *nebula.Value, err error) {
 		nval := nebula.NullType___NULL__
 		value.NVal = &nval
 	} else if v, ok := any.([]interface{}); ok {
-		nv, er := slice2Nlist([]interface{}(v))
+		nv, er := slice2Nlist([]interface{}(v), tz...)
 		if er != nil {
 			err = er
 		}
 		value.LVal = nv
 	} else if v, ok := any.(map[string]interface{}); ok {
-		nv, er := map2Nmap(map[string]interface{}(v))
+		nv, er := map2Nmap(map[string]interface{}(v), tz...)
 		if er != nil {
 			err = er
 		}
 		value.MVal = nv
 	} else if v, ok := any.(nebula.Value); ok {
-		value = &v
+		*value = v
 	} else if v, ok := any.(nebula.Date); ok {
 		value.SetDVal(&v)
 	} else if v, ok := any.(nebula.DateTime); ok {
@@ -469,9 +763,15 @@ func value2Nvalue(any interface{}) (value *nebula.Value, err error) {
 		value.SetTVal(&v)
 	} else if v, ok := any.(nebula.Geography); ok {
 		value.SetGgVal(&v)
+	} else if rv := reflect.ValueOf(any); rv.Kind() == reflect.Struct {
+		nv, er := struct2Nmap(rv, tz...)
+		if er != nil {
+			err = er
+		}
+		value.MVal = nv
 	} else {
 		// unsupported other Value type, use this function carefully
-		err = fmt.Errorf("Only support convert boolean/float/int/string/map/list to nebula.Value but %T", any)
+		err = fmt.Errorf("Only support convert boolean/float/int/string/map/list/struct to nebula.Value but %T", any)
 	}
 	return
 }
@@ -0,0 +1,97 @@
+/*
+ *
+ * Copyright (c) 2020 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License.
+ *
+ */
+
+package nebula_go
+
+import (
+	"context"
+
+	"github.com/JimWen/nebula-go/v3/nebula"
+)
+
+// defaultPagingBatchSize is used when PagingOptions.BatchSize is not set or <= 0.
+const defaultPagingBatchSize = 1000
+
+// PagingOptions controls how ExecutePaged/ExecutePagedWithParameter hand rows
+// back to the caller.
+type PagingOptions struct {
+	// BatchSize is the number of rows delivered per ResultPage.
+	// Defaults to defaultPagingBatchSize when <= 0.
+	BatchSize int
+}
+
+// ResultPage is one page of rows sliced off a ResultSet that's already fully
+// in memory - see ExecutePagedWithParameter. Err is set, and Rows/ColNames
+// left empty, when paging stops early because of a cancelled context.
+type ResultPage struct {
+	ColNames []string
+	Rows     []*nebula.Row
+	Err      error
+}
+
+// ExecutePaged is like Execute but hands rows back in pages over a channel
+// instead of returning a fully materialized ResultSet in one go. The channel
+// is closed once all rows have been delivered, the context is done, or an
+// error is produced. Callers should keep draining the channel until it is
+// closed so the producing goroutine can exit.
+func (session *Session) ExecutePaged(ctx context.Context, stmt string, opts ...PagingOptions) (<-chan *ResultPage, error) {
+	return session.ExecutePagedWithParameter(ctx, stmt, map[string]interface{}{}, opts...)
+}
+
+// ExecutePagedWithParameter is ExecutePaged with bound parameters, see
+// ExecuteWithParameter for parameter conversion rules.
+//
+// This is client-side paging only, not a streaming RPC: the graph service's
+// execute call returns its response in a single thrift reply, there is no
+// server-side cursor to page through, and the whole ResultSet is
+// materialized in memory by ExecuteWithParameterContext before paging
+// starts. It does not reduce peak memory use or protect against OOM on a
+// result set that's too large to hold at once - for that, the query itself
+// needs a LIMIT/pagination clause. What it does buy callers is a smaller
+// per-page processing chunk: the ResultSet's rows are sliced into pages so a
+// caller iterating a large-but-memory-fits traversal can process it
+// incrementally, and ctx is threaded into the underlying query (via
+// ExecuteWithParameterContext) so a cancelled or expired context also stops
+// the query's retry/reconnect loop, and not just the draining of
+// already-fetched rows; see ExecuteWithContext for how far it reaches into a
+// call already in flight.
+func (session *Session) ExecutePagedWithParameter(ctx context.Context, stmt string, params map[string]interface{}, opts ...PagingOptions) (<-chan *ResultPage, error) {
+	batchSize := defaultPagingBatchSize
+	if len(opts) > 0 && opts[0].BatchSize > 0 {
+		batchSize = opts[0].BatchSize
+	}
+
+	resSet, err := session.ExecuteWithParameterContext(ctx, stmt, params)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan *ResultPage)
+	go func() {
+		defer close(out)
+
+		rows := resSet.GetRows()
+		colNames := resSet.GetColNames()
+
+		for start := 0; start < len(rows); start += batchSize {
+			end := start + batchSize
+			if end > len(rows) {
+				end = len(rows)
+			}
+
+			select {
+			case <-ctx.Done():
+				out <- &ResultPage{Err: ctx.Err()}
+				return
+			case out <- &ResultPage{ColNames: colNames, Rows: rows[start:end]}:
+			}
+		}
+	}()
+
+	return out, nil
+}